@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/derat/fix_mp3/mp3fix"
+)
+
+func runStripFooterCommand(kind string, args []string, force bool) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: %v -force -strip-footer=%v FILENAME", os.Args[0], kind)
+	}
+	var want mp3fix.FooterKind
+	switch kind {
+	case "id3v1":
+		want = mp3fix.FooterID3v1
+	case "apev2":
+		want = mp3fix.FooterAPEv2
+	default:
+		return fmt.Errorf("unknown footer kind %q (want id3v1 or apev2)", kind)
+	}
+
+	fn := args[0]
+	f, err := os.OpenFile(fn, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	footers, err := mp3fix.ReadFooters(f, info.Size())
+	if err != nil {
+		return fmt.Errorf("failed to read footers: %v", err)
+	}
+	for _, ft := range footers {
+		if ft.Kind != want {
+			continue
+		}
+		if !force {
+			log.Printf("Would strip %v footer at 0x%x (%d bytes); rerun with -force to write", ft.Kind, ft.Offset, ft.Size)
+			return nil
+		}
+		if ft.Kind == mp3fix.FooterAPEv2 && len(footers) > 1 {
+			// An ID3v1 tag follows the APEv2 tag: move it down over the
+			// APEv2 tag so that only the APEv2 tag is removed.
+			v1 := footers[0]
+			b := make([]byte, v1.Size)
+			if _, err := f.ReadAt(b, v1.Offset); err != nil {
+				return err
+			}
+			if _, err := f.WriteAt(b, ft.Offset); err != nil {
+				return err
+			}
+			return f.Truncate(ft.Offset + v1.Size)
+		}
+		return mp3fix.StripFooter(f, ft)
+	}
+	return fmt.Errorf("no %v footer present", want)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/derat/fix_mp3/mp3fix"
+)
+
+// runTagCommand dispatches the list/get/set/strip subcommands that operate
+// directly on a file's ID3v2 tag.
+func runTagCommand(cmd string, args []string, force bool) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: %v %v FILENAME [ARG]", os.Args[0], cmd)
+	}
+	fn := args[0]
+
+	f, err := os.OpenFile(fn, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	t, err := mp3fix.ReadTag(f)
+	if err != nil {
+		return fmt.Errorf("failed to read tag: %v", err)
+	}
+
+	switch cmd {
+	case "list":
+		for _, fr := range t.Frames {
+			if text, err := fr.Text(); err == nil {
+				fmt.Printf("%v=%v\n", fr.ID, text)
+			} else {
+				fmt.Printf("%v=<%d bytes>\n", fr.ID, len(fr.Data))
+			}
+		}
+		return nil
+	case "get":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: %v get FILENAME FRAME", os.Args[0])
+		}
+		fr := t.Get(args[1])
+		if fr == nil {
+			return fmt.Errorf("no %v frame", args[1])
+		}
+		text, err := fr.Text()
+		if err != nil {
+			return err
+		}
+		fmt.Println(text)
+		return nil
+	case "set":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: %v set FILENAME FRAME=VALUE", os.Args[0])
+		}
+		parts := bytes.SplitN([]byte(args[1]), []byte("="), 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("expected FRAME=VALUE, got %q", args[1])
+		}
+		t.Set(string(parts[0]), string(parts[1]))
+		if !force {
+			return fmt.Errorf("not writing without -force")
+		}
+		return t.Write(f)
+	case "strip":
+		t.Strip()
+		if !force {
+			return fmt.Errorf("not writing without -force")
+		}
+		return t.Write(f)
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
@@ -0,0 +1,135 @@
+package mp3fix
+
+import (
+	"fmt"
+	"io"
+	"log"
+)
+
+const defaultMaxScanBytes = 2048
+
+// ReaderWriterAt is satisfied by anything Fixer.Repair can both inspect and
+// patch in place, such as *os.File.
+type ReaderWriterAt interface {
+	io.ReaderAt
+	WriterAt
+}
+
+// Result holds what Analyze found in an MP3 file.
+type Result struct {
+	// HeaderSize is where the ID3v2 header's own tag-size field says
+	// audio data should start.
+	HeaderSize int64
+	// FrameOffset is where a valid MPEG frame was actually found. It
+	// differs from HeaderSize when the tag's recorded size is wrong.
+	FrameOffset int64
+	Frame       FrameHeader
+	Footers     []*Footer
+	VBR         *VBRHeader
+}
+
+// Mismatched reports whether the ID3v2 header's recorded tag size disagrees
+// with where the first audio frame actually starts.
+func (res *Result) Mismatched() bool {
+	return res.FrameOffset != res.HeaderSize
+}
+
+// Fixer analyzes and repairs the tag-size mismatch that this package was
+// originally written to address. Analyze reports any Xing/Info/VBRI header
+// in the first frame; Repair does not rewrite that header's byte count or
+// TOC.
+type Fixer struct {
+	// MaxScanBytes bounds how far past the declared tag end Analyze will
+	// scan looking for the real start of audio. Defaults to 2048 if
+	// zero.
+	MaxScanBytes int64
+	// DryRun causes Repair to report what it would change without
+	// writing anything.
+	DryRun bool
+	// Logger receives progress messages, if non-nil.
+	Logger *log.Logger
+}
+
+func (fx *Fixer) maxScanBytes() int64 {
+	if fx.MaxScanBytes > 0 {
+		return fx.MaxScanBytes
+	}
+	return defaultMaxScanBytes
+}
+
+func (fx *Fixer) logf(format string, args ...interface{}) {
+	if fx.Logger != nil {
+		fx.Logger.Printf(format, args...)
+	}
+}
+
+// Analyze reads r (which has the given total size) and reports its ID3v2
+// header, the actual location of the first audio frame, any trailing
+// footers, and any Xing/Info/VBRI header. It performs no writes.
+func (fx *Fixer) Analyze(r io.ReaderAt, size int64) (*Result, error) {
+	res := &Result{}
+
+	headerSize, major, minor, err := ReadHeader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %v", err)
+	}
+	res.HeaderSize = headerSize
+	fx.logf("ID3 v2.%d.%d, header size 0x%x", major, minor, headerSize)
+
+	if footers, err := ReadFooters(r, size); err != nil {
+		fx.logf("Failed to read trailing tags: %v", err)
+	} else {
+		res.Footers = footers
+		for _, ft := range footers {
+			fx.logf("Found %v footer at 0x%x (%d bytes)", ft.Kind, ft.Offset, ft.Size)
+		}
+	}
+
+	frameOffset, hdr, err := FindFirstFrame(r, headerSize, 1)
+	if err != nil {
+		fx.logf("Failed to read frame at header boundary: %v", err)
+		fx.logf("Scanning for first MP3 frame...")
+		if frameOffset, hdr, err = FindFirstFrame(r, headerSize, fx.maxScanBytes()); err != nil {
+			return nil, fmt.Errorf("didn't find MP3 frame in first %d bytes starting at 0x%x: %v", fx.maxScanBytes(), headerSize, err)
+		}
+	}
+	res.FrameOffset = frameOffset
+	res.Frame = hdr
+	fx.logf("Found MP3 frame at 0x%x (%+v)", frameOffset, hdr)
+
+	vbr, err := ReadVBRHeader(r, frameOffset, hdr)
+	if err != nil {
+		fx.logf("Failed to read VBR header: %v", err)
+	} else if vbr != nil {
+		res.VBR = vbr
+		fx.logf("Found %v header: %d frames, %d bytes", vbr.Kind, vbr.Frames, vbr.Bytes)
+	}
+
+	return res, nil
+}
+
+// Repair rewrites rw's ID3v2 tag-size field to match res.FrameOffset. It's a
+// no-op if res isn't Mismatched. Honors fx.DryRun by logging the change
+// instead of writing it.
+//
+// Nothing here moves audio bytes around, so a Xing/Info/VBRI header found
+// by Analyze (res.VBR) stays accurate: it was written by the encoder
+// relative to the frame's actual on-disk position, not to whatever the
+// ID3v2 header's (buggy) tag-size field claimed, so it needs no adjustment
+// when that field is corrected.
+func (fx *Fixer) Repair(rw ReaderWriterAt, res *Result) error {
+	if !res.Mismatched() {
+		return nil
+	}
+
+	if fx.DryRun {
+		fx.logf("Would write tag size 0x%x (dry run)", res.FrameOffset-ID3HeaderSize)
+		return nil
+	}
+	if err := WriteTagSize(rw, res.FrameOffset-ID3HeaderSize); err != nil {
+		return fmt.Errorf("failed to write updated tag size: %v", err)
+	}
+	fx.logf("Wrote tag size 0x%x", res.FrameOffset-ID3HeaderSize)
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package mp3fix
+
+import (
+	"bytes"
+	"testing"
+)
+
+// buildID3File assembles a minimal file consisting of an ID3v2 header, a
+// tagSize-byte (zero-padded) body, an optional v2.4 footer mirroring the
+// header, and trailing audio bytes.
+func buildID3File(t *testing.T, major, minor, flags byte, tagSize int, footer bool, audio []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("ID3")
+	buf.WriteByte(major)
+	buf.WriteByte(minor)
+	buf.WriteByte(flags)
+	sz := encodeSyncsafe(tagSize)
+	buf.Write(sz[:])
+	buf.Write(make([]byte, tagSize))
+	if footer {
+		buf.WriteString("3DI") // v2.4 footer magic ("ID3" reversed)
+		buf.WriteByte(major)
+		buf.WriteByte(minor)
+		buf.WriteByte(flags)
+		buf.Write(sz[:])
+	}
+	buf.Write(audio)
+	return buf.Bytes()
+}
+
+func TestFixerAnalyze(t *testing.T) {
+	frame := makeHeaderBytes(9, 0, 0, false, false) // unprotected, so a single valid header suffices
+
+	for _, tc := range []struct {
+		name   string
+		major  byte
+		flags  byte
+		footer bool
+	}{
+		{"no flags", 3, 0, false},
+		{"unsynchronisation flag", 3, id3FlagUnsynch, false},
+		{"extended header flag", 3, id3FlagExtHeader, false},
+		{"v2.4 footer flag", 4, id3FlagFooter, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data := buildID3File(t, tc.major, 0, tc.flags, 20, tc.footer, frame)
+			fx := &Fixer{}
+			res, err := fx.Analyze(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("Analyze failed: %v", err)
+			}
+			if res.Mismatched() {
+				t.Errorf("Analyze reported a mismatch (HeaderSize=0x%x, FrameOffset=0x%x) for a correctly-sized tag",
+					res.HeaderSize, res.FrameOffset)
+			}
+		})
+	}
+}
+
+// buildMismatchedFile builds a file whose header claims a claimedBodySize-byte
+// tag, but whose real first audio frame starts garbageSize bytes after that,
+// simulating a tag whose recorded size is wrong.
+func buildMismatchedFile(claimedBodySize, garbageSize int, frame []byte) []byte {
+	data := make([]byte, ID3HeaderSize+claimedBodySize+garbageSize+len(frame))
+	copy(data[0:3], "ID3")
+	data[3], data[4], data[5] = 3, 0, 0
+	sz := encodeSyncsafe(claimedBodySize)
+	copy(data[6:10], sz[:])
+	copy(data[ID3HeaderSize+claimedBodySize+garbageSize:], frame)
+	return data
+}
+
+func TestFixerRepair(t *testing.T) {
+	frame := makeHeaderBytes(9, 0, 0, false, false)
+	const claimedBodySize, garbageSize = 10, 5
+	data := buildMismatchedFile(claimedBodySize, garbageSize, frame)
+
+	buf := &memBuf{b: data}
+	fx := &Fixer{}
+	res, err := fx.Analyze(buf, int64(len(data)))
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if !res.Mismatched() {
+		t.Fatalf("Analyze didn't detect the tag-size mismatch")
+	}
+	wantFrameOffset := int64(ID3HeaderSize + claimedBodySize + garbageSize)
+	if res.FrameOffset != wantFrameOffset {
+		t.Fatalf("Analyze found frame at 0x%x, want 0x%x", res.FrameOffset, wantFrameOffset)
+	}
+
+	if err := fx.Repair(buf, res); err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+
+	res2, err := fx.Analyze(buf, int64(len(data)))
+	if err != nil {
+		t.Fatalf("re-Analyze after Repair failed: %v", err)
+	}
+	if res2.Mismatched() {
+		t.Errorf("tag still reports a mismatch after Repair (HeaderSize=0x%x, FrameOffset=0x%x)",
+			res2.HeaderSize, res2.FrameOffset)
+	}
+}
+
+func TestFixerRepairDryRun(t *testing.T) {
+	frame := makeHeaderBytes(9, 0, 0, false, false)
+	data := buildMismatchedFile(10, 5, frame)
+	orig := append([]byte{}, data...)
+
+	buf := &memBuf{b: data}
+	fx := &Fixer{DryRun: true}
+	res, err := fx.Analyze(buf, int64(len(data)))
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if !res.Mismatched() {
+		t.Fatalf("Analyze didn't detect the tag-size mismatch")
+	}
+	if err := fx.Repair(buf, res); err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+	if !bytes.Equal(buf.b, orig) {
+		t.Errorf("DryRun Repair modified the file")
+	}
+}
@@ -0,0 +1,149 @@
+package mp3fix
+
+import (
+	"fmt"
+	"io"
+)
+
+// FooterKind identifies the type of trailing tag container found at the end
+// of an MP3 file.
+type FooterKind int
+
+const (
+	FooterID3v1 FooterKind = iota
+	FooterAPEv2
+)
+
+func (k FooterKind) String() string {
+	switch k {
+	case FooterID3v1:
+		return "ID3v1"
+	case FooterAPEv2:
+		return "APEv2"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	id3v1Size      = 128
+	apeFooterSize  = 32
+	apePreambleLen = 8
+)
+
+// Footer describes a trailing tag container (ID3v1/v1.1 or APEv2) found at
+// the end of a file, separate from the ID3v2 header this package otherwise
+// inspects.
+type Footer struct {
+	Kind   FooterKind
+	Offset int64
+	Size   int64
+
+	// Fields parsed from an ID3v1/v1.1 tag. Only valid when Kind is
+	// FooterID3v1.
+	Title, Artist, Album, Comment string
+	Year                          string
+	Track                         int // 0 if not an ID3v1.1 tag
+	Genre                         byte
+}
+
+// ReadFooters detects and parses any trailing ID3v1/v1.1 and/or APEv2 tags
+// at the end of r, which has the given total size. An ID3v1 tag is checked
+// for first, and if one is found the APEv2 search ends where it starts,
+// since APEv2 tags are commonly written just before a trailing ID3v1 tag.
+// An ID3v1 footer, when present, is therefore the first element returned.
+func ReadFooters(r io.ReaderAt, size int64) ([]*Footer, error) {
+	var footers []*Footer
+
+	if ft, err := readID3v1(r, size); err != nil {
+		return nil, err
+	} else if ft != nil {
+		footers = append(footers, ft)
+		size = ft.Offset // APEv2, if present, precedes the ID3v1 tag
+	}
+
+	if ft, err := readAPEv2(r, size); err != nil {
+		return nil, err
+	} else if ft != nil {
+		footers = append(footers, ft)
+	}
+
+	return footers, nil
+}
+
+func readID3v1(r io.ReaderAt, end int64) (*Footer, error) {
+	if end < id3v1Size {
+		return nil, nil
+	}
+	b := make([]byte, id3v1Size)
+	if _, err := r.ReadAt(b, end-id3v1Size); err != nil {
+		return nil, err
+	}
+	if string(b[0:3]) != "TAG" {
+		return nil, nil
+	}
+
+	trim := func(b []byte) string {
+		for i, c := range b {
+			if c == 0 {
+				return string(b[:i])
+			}
+		}
+		return string(b)
+	}
+
+	ft := &Footer{
+		Kind:    FooterID3v1,
+		Offset:  end - id3v1Size,
+		Size:    id3v1Size,
+		Title:   trim(b[3:33]),
+		Artist:  trim(b[33:63]),
+		Album:   trim(b[63:93]),
+		Year:    trim(b[93:97]),
+		Comment: trim(b[97:127]),
+		Genre:   b[127],
+	}
+	// ID3v1.1: a zero byte at offset 125 means byte 126 holds the track
+	// number rather than being part of the comment.
+	if b[125] == 0 && b[126] != 0 {
+		ft.Track = int(b[126])
+		ft.Comment = trim(b[97:125])
+	}
+	return ft, nil
+}
+
+func readAPEv2(r io.ReaderAt, end int64) (*Footer, error) {
+	if end < apeFooterSize {
+		return nil, nil
+	}
+	b := make([]byte, apeFooterSize)
+	if _, err := r.ReadAt(b, end-apeFooterSize); err != nil {
+		return nil, err
+	}
+	if string(b[0:apePreambleLen]) != "APETAGEX" {
+		return nil, nil
+	}
+
+	// The footer's tag-size field already covers the footer itself (but
+	// not a mirrored header, which is optional and which we don't look
+	// for), so it shouldn't be added again here.
+	tagSize := beUint32LE(b[12:16])
+	return &Footer{
+		Kind:   FooterAPEv2,
+		Offset: end - int64(tagSize),
+		Size:   int64(tagSize),
+	}, nil
+}
+
+func beUint32LE(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
+
+// StripFooter removes the trailing tag described by ft by truncating t at
+// ft.Offset.
+func StripFooter(t Truncater, ft *Footer) error {
+	if err := t.Truncate(ft.Offset); err != nil {
+		return fmt.Errorf("failed to strip %v footer: %v", ft.Kind, err)
+	}
+	return nil
+}
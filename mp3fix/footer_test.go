@@ -0,0 +1,109 @@
+package mp3fix
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadFooters(t *testing.T) {
+	makeID3v1 := func(title string, track int) []byte {
+		b := make([]byte, id3v1Size)
+		copy(b[0:3], "TAG")
+		copy(b[3:33], title)
+		if track > 0 {
+			b[125] = 0
+			b[126] = byte(track)
+		}
+		return b
+	}
+
+	makeAPEv2 := func(bodySize int) []byte {
+		b := make([]byte, apeFooterSize)
+		copy(b[0:8], "APETAGEX")
+		// The APEv2 size field covers the footer itself.
+		beutil32LE(b[12:16], uint32(apeFooterSize+bodySize))
+		return b
+	}
+
+	t.Run("no footers", func(t *testing.T) {
+		audio := bytes.Repeat([]byte{0x00}, 64)
+		got, err := ReadFooters(bytes.NewReader(audio), int64(len(audio)))
+		if err != nil {
+			t.Fatalf("ReadFooters failed: %v", err)
+		}
+		if len(got) != 0 {
+			t.Errorf("ReadFooters found %d footers, want 0", len(got))
+		}
+	})
+
+	t.Run("id3v1 only", func(t *testing.T) {
+		audio := bytes.Repeat([]byte{0x00}, 64)
+		data := append(audio, makeID3v1("Some Title", 3)...)
+		got, err := ReadFooters(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("ReadFooters failed: %v", err)
+		}
+		if len(got) != 1 || got[0].Kind != FooterID3v1 {
+			t.Fatalf("ReadFooters = %+v, want one ID3v1 footer", got)
+		}
+		if got[0].Offset != int64(len(audio)) || got[0].Size != id3v1Size {
+			t.Errorf("ID3v1 footer = {Offset:%d Size:%d}, want {Offset:%d Size:%d}",
+				got[0].Offset, got[0].Size, len(audio), id3v1Size)
+		}
+		if got[0].Title != "Some Title" || got[0].Track != 3 {
+			t.Errorf("ID3v1 footer = {Title:%q Track:%d}, want {Title:%q Track:%d}",
+				got[0].Title, got[0].Track, "Some Title", 3)
+		}
+	})
+
+	t.Run("apev2 only", func(t *testing.T) {
+		audio := bytes.Repeat([]byte{0x00}, 64)
+		apeBodySize := 20
+		data := append(append(audio, bytes.Repeat([]byte{0x00}, apeBodySize)...), makeAPEv2(apeBodySize)...)
+		got, err := ReadFooters(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("ReadFooters failed: %v", err)
+		}
+		if len(got) != 1 || got[0].Kind != FooterAPEv2 {
+			t.Fatalf("ReadFooters = %+v, want one APEv2 footer", got)
+		}
+		// This exercises the footer-size double-counting regression: Size
+		// must be exactly the tag-size field's value, and Offset must point
+		// at the start of that same span, not apeFooterSize bytes earlier.
+		wantSize := int64(apeFooterSize + apeBodySize)
+		wantOffset := int64(len(data)) - wantSize
+		if got[0].Size != wantSize || got[0].Offset != wantOffset {
+			t.Errorf("APEv2 footer = {Offset:%d Size:%d}, want {Offset:%d Size:%d}",
+				got[0].Offset, got[0].Size, wantOffset, wantSize)
+		}
+	})
+
+	t.Run("apev2 followed by id3v1", func(t *testing.T) {
+		audio := bytes.Repeat([]byte{0x00}, 64)
+		apeBodySize := 20
+		data := append(audio, bytes.Repeat([]byte{0x00}, apeBodySize)...)
+		data = append(data, makeAPEv2(apeBodySize)...)
+		data = append(data, makeID3v1("Title", 0)...)
+
+		got, err := ReadFooters(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("ReadFooters failed: %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("ReadFooters found %d footers, want 2", len(got))
+		}
+		if got[0].Kind != FooterID3v1 || got[1].Kind != FooterAPEv2 {
+			t.Errorf("ReadFooters kinds = [%v %v], want [ID3v1 APEv2]", got[0].Kind, got[1].Kind)
+		}
+		if got[1].Offset != int64(len(audio)) {
+			t.Errorf("APEv2 footer offset = %d, want %d", got[1].Offset, len(audio))
+		}
+	})
+}
+
+func beutil32LE(b []byte, v uint32) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+	b[3] = byte(v >> 24)
+}
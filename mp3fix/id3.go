@@ -0,0 +1,388 @@
+package mp3fix
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf16"
+)
+
+// ReadHeader reads the 10-byte ID3v2 header at the start of r and returns
+// the offset at which the tag ends (and audio data is expected to begin),
+// along with the tag's major and minor version numbers. The unsynchronisation,
+// extended-header, and experimental flags don't affect the tag's on-disk
+// layout, but a v2.4 footer (a 10-byte mirror of the header) does: it's
+// accounted for in headerSize.
+func ReadHeader(r io.ReaderAt) (headerSize int64, major, minor byte, err error) {
+	b := make([]byte, ID3HeaderSize)
+	if _, err := r.ReadAt(b, 0); err != nil {
+		return 0, 0, 0, err
+	}
+
+	if b[0] != 'I' || b[1] != 'D' || b[2] != '3' {
+		return 0, 0, 0, fmt.Errorf("File starts with %v instead of \"ID3\"", b[0:3])
+	}
+	major, minor = b[3], b[4]
+	if major != 3 && major != 4 {
+		return 0, 0, 0, fmt.Errorf("Unsupported major version %d", major)
+	}
+	flags := b[5]
+	if flags&^(id3FlagUnsynch|id3FlagExtHeader|id3FlagExperimental|id3FlagFooter) != 0 {
+		return 0, 0, 0, fmt.Errorf("Unsupported flags 0x%x", flags)
+	}
+
+	tagSize, err := decodeSyncsafe(b[6:10])
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	headerSize = int64(tagSize) + ID3HeaderSize
+	if major == 4 && flags&id3FlagFooter != 0 {
+		headerSize += ID3HeaderSize
+	}
+	return headerSize, major, minor, nil
+}
+
+// WriteTagSize rewrites the 4-byte syncsafe tag-size field of the ID3v2
+// header at the start of w, without touching anything else in the file.
+func WriteTagSize(w WriterAt, size int64) error {
+	b := encodeSyncsafe(int(size))
+	_, err := w.WriteAt(b[:], 6)
+	return err
+}
+
+// Frame holds a single ID3v2 frame as read from a tag. Data is the frame's
+// raw, still-encoded payload; use Text to decode text frames.
+type Frame struct {
+	ID    string
+	Flags uint16
+	Data  []byte
+}
+
+// Text decodes a text-information frame's (e.g. TIT2, TALB, TPE1) payload
+// according to its leading text-encoding byte. It returns an error if the
+// frame has no payload or uses an encoding byte this package doesn't
+// recognize.
+func (fr *Frame) Text() (string, error) {
+	if len(fr.Data) < 1 {
+		return "", fmt.Errorf("%v frame has no data", fr.ID)
+	}
+	return decodeText(fr.Data[0], fr.Data[1:])
+}
+
+// Tag represents a fully-parsed ID3v2 tag, including its frames.
+type Tag struct {
+	MajorVersion byte
+	MinorVersion byte
+	Flags        byte
+	Frames       []*Frame
+
+	// size is the tag size (as read from the header, excluding the
+	// 10-byte header itself) that the tag originally occupied in the
+	// file. It's used by Write to know how much space is available
+	// without moving the following audio data.
+	size int64
+}
+
+const (
+	id3FlagUnsynch      = 0x80
+	id3FlagExtHeader    = 0x40
+	id3FlagExperimental = 0x20
+	id3FlagFooter       = 0x10 // v2.4 only
+	frameHeaderSize     = 10
+)
+
+// ReadTag reads and parses the ID3v2 tag at the start of r.
+func ReadTag(r io.ReaderAt) (*Tag, error) {
+	hdr := make([]byte, ID3HeaderSize)
+	if _, err := r.ReadAt(hdr, 0); err != nil {
+		return nil, err
+	}
+	if hdr[0] != 'I' || hdr[1] != 'D' || hdr[2] != '3' {
+		return nil, fmt.Errorf("File starts with %v instead of \"ID3\"", hdr[0:3])
+	}
+
+	t := &Tag{
+		MajorVersion: hdr[3],
+		MinorVersion: hdr[4],
+		Flags:        hdr[5],
+	}
+	if t.MajorVersion != 3 && t.MajorVersion != 4 {
+		return nil, fmt.Errorf("Unsupported major version %d", t.MajorVersion)
+	}
+
+	size, err := decodeSyncsafe(hdr[6:10])
+	if err != nil {
+		return nil, err
+	}
+	t.size = int64(size)
+
+	body := make([]byte, size)
+	if _, err := r.ReadAt(body, ID3HeaderSize); err != nil {
+		return nil, err
+	}
+	if t.Flags&id3FlagUnsynch != 0 {
+		body = removeUnsync(body)
+	}
+
+	pos := 0
+	if t.Flags&id3FlagExtHeader != 0 {
+		if len(body) < 4 {
+			return nil, fmt.Errorf("tag too short for extended header")
+		}
+		if t.MajorVersion == 4 {
+			// The v2.4 extended header size is syncsafe and counts
+			// the whole extended header, including itself.
+			extSize, err := decodeSyncsafe(body[0:4])
+			if err != nil {
+				return nil, err
+			}
+			pos += extSize
+		} else {
+			// The v2.3 extended header size excludes the 4-byte
+			// size field itself.
+			pos += 4 + int(beUint32(body[0:4]))
+		}
+		if pos > len(body) {
+			return nil, fmt.Errorf("extended header size %d overruns tag", pos)
+		}
+	}
+
+	for pos+frameHeaderSize <= len(body) {
+		id := string(body[pos : pos+4])
+		if id[0] == 0 {
+			break // padding
+		}
+
+		var frameSize int
+		if t.MajorVersion == 4 {
+			frameSize, err = decodeSyncsafe(body[pos+4 : pos+8])
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			frameSize = int(beUint32(body[pos+4 : pos+8]))
+		}
+		flags := beUint16(body[pos+8 : pos+10])
+		pos += frameHeaderSize
+
+		if pos+frameSize > len(body) {
+			return nil, fmt.Errorf("%v frame size %d overruns tag", id, frameSize)
+		}
+		data := make([]byte, frameSize)
+		copy(data, body[pos:pos+frameSize])
+		pos += frameSize
+
+		t.Frames = append(t.Frames, &Frame{ID: id, Flags: flags, Data: data})
+	}
+
+	return t, nil
+}
+
+// Get returns the first frame with the supplied ID, or nil if none exists.
+func (t *Tag) Get(id string) *Frame {
+	for _, fr := range t.Frames {
+		if fr.ID == id {
+			return fr
+		}
+	}
+	return nil
+}
+
+// Set replaces (or adds) the first frame with the given ID with a new
+// text-information frame encoded as ISO-8859-1, matching val. Characters
+// outside ISO-8859-1 are stored as '?'.
+func (t *Tag) Set(id, val string) {
+	data := []byte{0x0}
+	for _, r := range val {
+		if r > 0xff {
+			r = '?'
+		}
+		data = append(data, byte(r))
+	}
+	if fr := t.Get(id); fr != nil {
+		fr.Data = data
+		return
+	}
+	t.Frames = append(t.Frames, &Frame{ID: id, Data: data})
+}
+
+// Remove deletes all frames with the given ID, returning the number removed.
+func (t *Tag) Remove(id string) int {
+	var kept []*Frame
+	n := 0
+	for _, fr := range t.Frames {
+		if fr.ID == id {
+			n++
+			continue
+		}
+		kept = append(kept, fr)
+	}
+	t.Frames = kept
+	return n
+}
+
+// Strip removes all frames from the tag.
+func (t *Tag) Strip() {
+	t.Frames = nil
+}
+
+// Write serializes the tag and writes it to the start of w. The tag is
+// padded with zero bytes (or rejected, if it no longer fits) to continue
+// occupying exactly the space it did when read, so the following audio data
+// doesn't need to be moved.
+func (t *Tag) Write(w WriterAt) error {
+	var buf bytes.Buffer
+	for _, fr := range t.Frames {
+		buf.WriteString(fr.ID)
+		var sizeBytes [4]byte
+		if t.MajorVersion == 4 {
+			sizeBytes = encodeSyncsafe(len(fr.Data))
+		} else {
+			beutil32(sizeBytes[:], uint32(len(fr.Data)))
+		}
+		buf.Write(sizeBytes[:])
+		var flagBytes [2]byte
+		beutil16(flagBytes[:], fr.Flags)
+		buf.Write(flagBytes[:])
+		buf.Write(fr.Data)
+	}
+
+	out := buf.Bytes()
+	if t.Flags&id3FlagUnsynch != 0 {
+		out = applyUnsync(out)
+	}
+
+	size := int64(len(out))
+	if size > t.size {
+		return fmt.Errorf("tag grew from %d to %d bytes; rewriting a larger tag isn't supported", t.size, size)
+	}
+	out = append(out, make([]byte, t.size-size)...)
+
+	hdr := make([]byte, ID3HeaderSize)
+	copy(hdr[0:3], "ID3")
+	hdr[3] = t.MajorVersion
+	hdr[4] = t.MinorVersion
+	hdr[5] = t.Flags &^ id3FlagExtHeader // extended headers aren't reproduced
+	sz := encodeSyncsafe(int(t.size))
+	copy(hdr[6:10], sz[:])
+
+	if _, err := w.WriteAt(hdr, 0); err != nil {
+		return err
+	}
+	_, err := w.WriteAt(out, ID3HeaderSize)
+	return err
+}
+
+// removeUnsync reverses ID3v2 unsynchronisation: every 0x00 byte that
+// immediately follows a 0xff byte was inserted to prevent an MPEG sync word
+// (or an existing 0xff 0x00) from appearing in the tag, and is dropped.
+func removeUnsync(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		out = append(out, b[i])
+		if b[i] == 0xff && i+1 < len(b) && b[i+1] == 0x00 {
+			i++
+		}
+	}
+	return out
+}
+
+// applyUnsync applies ID3v2 unsynchronisation: a 0x00 byte is inserted after
+// every 0xff byte that's followed by either 0x00 or a byte with its top
+// three bits set (which would otherwise look like the start of an MPEG
+// sync word), so the reverse transform in removeUnsync can restore the
+// original bytes.
+func applyUnsync(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for i := 0; i < len(b); i++ {
+		out = append(out, b[i])
+		if b[i] == 0xff && i+1 < len(b) && (b[i+1] == 0x00 || b[i+1]&0xe0 == 0xe0) {
+			out = append(out, 0x00)
+		}
+	}
+	return out
+}
+
+func decodeSyncsafe(b []byte) (int, error) {
+	v := 0
+	for _, c := range b {
+		if c&0x80 != 0 {
+			return 0, fmt.Errorf("High bit(s) set in size %v", b)
+		}
+		v = v<<7 | int(c&0x7f)
+	}
+	return v, nil
+}
+
+func encodeSyncsafe(v int) [4]byte {
+	var b [4]byte
+	for i := 3; i >= 0; i-- {
+		b[i] = byte(v & 0x7f)
+		v >>= 7
+	}
+	return b
+}
+
+func beUint32(b []byte) uint32 {
+	return uint32(b[0])<<24 | uint32(b[1])<<16 | uint32(b[2])<<8 | uint32(b[3])
+}
+
+func beUint16(b []byte) uint16 {
+	return uint16(b[0])<<8 | uint16(b[1])
+}
+
+func beutil32(b []byte, v uint32) {
+	b[0] = byte(v >> 24)
+	b[1] = byte(v >> 16)
+	b[2] = byte(v >> 8)
+	b[3] = byte(v)
+}
+
+func beutil16(b []byte, v uint16) {
+	b[0] = byte(v >> 8)
+	b[1] = byte(v)
+}
+
+// decodeText decodes a text-information frame payload (everything after the
+// leading encoding byte) per the ID3v2 text-encoding byte values: 0 is
+// ISO-8859-1, 1 is UTF-16 with a BOM, 2 is UTF-16BE without a BOM (v2.4
+// only), and 3 is UTF-8 (v2.4 only).
+func decodeText(enc byte, b []byte) (string, error) {
+	switch enc {
+	case 0x00:
+		b = bytes.TrimRight(b, "\x00")
+		rs := make([]rune, len(b))
+		for i, c := range b {
+			rs[i] = rune(c) // ISO-8859-1 bytes map directly to code points
+		}
+		return string(rs), nil
+	case 0x03:
+		return string(bytes.TrimRight(b, "\x00")), nil
+	case 0x01, 0x02:
+		if enc == 0x01 && len(b) >= 2 && b[0] == 0xff && b[1] == 0xfe {
+			b = b[2:]
+			return decodeUTF16(b, false), nil
+		} else if enc == 0x01 && len(b) >= 2 && b[0] == 0xfe && b[1] == 0xff {
+			b = b[2:]
+			return decodeUTF16(b, true), nil
+		}
+		return decodeUTF16(b, true), nil
+	default:
+		return "", fmt.Errorf("unsupported text encoding 0x%x", enc)
+	}
+}
+
+func decodeUTF16(b []byte, bigEndian bool) string {
+	if len(b)%2 != 0 {
+		b = b[:len(b)-1]
+	}
+	u16 := make([]uint16, 0, len(b)/2)
+	for i := 0; i < len(b); i += 2 {
+		if bigEndian {
+			u16 = append(u16, uint16(b[i])<<8|uint16(b[i+1]))
+		} else {
+			u16 = append(u16, uint16(b[i+1])<<8|uint16(b[i]))
+		}
+	}
+	return string(utf16.Decode(u16))
+}
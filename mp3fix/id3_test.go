@@ -0,0 +1,172 @@
+package mp3fix
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// memBuf is a minimal in-memory io.ReaderAt/WriterAt used to exercise
+// ReadTag/Tag.Write without touching the filesystem.
+type memBuf struct {
+	b []byte
+}
+
+func (m *memBuf) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(m.b)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.b[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (m *memBuf) WriteAt(p []byte, off int64) (int, error) {
+	n := copy(m.b[off:], p)
+	return n, nil
+}
+
+// buildTag encodes an ID3v2.3 tag containing the given text frames (id to
+// ISO-8859-1 value) into a tagSize-byte body, zero-padded to fill it.
+func buildTag(t *testing.T, flags byte, tagSize int, frames map[string]string) []byte {
+	t.Helper()
+	var body bytes.Buffer
+	for id, val := range frames {
+		body.WriteString(id)
+		data := append([]byte{0x0}, []byte(val)...)
+		var sizeBytes [4]byte
+		beutil32(sizeBytes[:], uint32(len(data)))
+		body.Write(sizeBytes[:])
+		var flagBytes [2]byte
+		body.Write(flagBytes[:])
+		body.Write(data)
+	}
+	if body.Len() > tagSize {
+		t.Fatalf("frames don't fit in %d-byte tag", tagSize)
+	}
+	out := body.Bytes()
+	if flags&id3FlagUnsynch != 0 {
+		out = applyUnsync(out)
+	}
+	// Growing past tagSize due to unsync stuffing isn't exercised here.
+	if len(out) > tagSize {
+		t.Fatalf("unsynced body grew past %d bytes", tagSize)
+	}
+	out = append(out, make([]byte, tagSize-len(out))...)
+
+	buf := make([]byte, ID3HeaderSize+tagSize)
+	copy(buf[0:3], "ID3")
+	buf[3] = 3 // major version
+	buf[4] = 0 // minor version
+	buf[5] = flags
+	sz := encodeSyncsafe(tagSize)
+	copy(buf[6:10], sz[:])
+	copy(buf[ID3HeaderSize:], out)
+	return buf
+}
+
+func TestReadTag(t *testing.T) {
+	t.Run("single text frame", func(t *testing.T) {
+		raw := buildTag(t, 0, 64, map[string]string{"TIT2": "Track Title"})
+		tag, err := ReadTag(&memBuf{b: raw})
+		if err != nil {
+			t.Fatalf("ReadTag failed: %v", err)
+		}
+		fr := tag.Get("TIT2")
+		if fr == nil {
+			t.Fatalf("TIT2 frame missing")
+		}
+		text, err := fr.Text()
+		if err != nil {
+			t.Fatalf("Text failed: %v", err)
+		}
+		if text != "Track Title" {
+			t.Errorf("TIT2 text = %q, want %q", text, "Track Title")
+		}
+	})
+
+	t.Run("bad magic", func(t *testing.T) {
+		raw := buildTag(t, 0, 16, nil)
+		raw[0] = 'X'
+		if _, err := ReadTag(&memBuf{b: raw}); err == nil {
+			t.Errorf("ReadTag succeeded despite bad magic")
+		}
+	})
+
+	t.Run("unsynchronised tag", func(t *testing.T) {
+		// 0xff 0xe0 in the frame payload would look like an MPEG sync word
+		// if left alone, and the existing 0xff 0x00 must be escaped so it
+		// survives, so encoding it requires unsynchronisation.
+		raw := buildTag(t, id3FlagUnsynch, 64, map[string]string{"TIT2": "\xff\x00\xff\xe0"})
+		tag, err := ReadTag(&memBuf{b: raw})
+		if err != nil {
+			t.Fatalf("ReadTag failed: %v", err)
+		}
+		fr := tag.Get("TIT2")
+		if fr == nil {
+			t.Fatalf("TIT2 frame missing")
+		}
+		want := []byte("\x00\xff\x00\xff\xe0") // leading byte is the ISO-8859-1 encoding marker
+		if !bytes.Equal(fr.Data, want) {
+			t.Errorf("TIT2 data = %x, want %x", fr.Data, want)
+		}
+	})
+}
+
+func TestTagWriteRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		flags  byte
+		frames map[string]string
+	}{
+		{"plain", 0, map[string]string{"TIT2": "Title", "TPE1": "Artist"}},
+		{"unsynchronised", id3FlagUnsynch, map[string]string{"TIT2": "\xff\x00name"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			raw := buildTag(t, tc.flags, 128, tc.frames)
+			orig := append([]byte{}, raw...)
+
+			buf := &memBuf{b: raw}
+			tag, err := ReadTag(buf)
+			if err != nil {
+				t.Fatalf("ReadTag failed: %v", err)
+			}
+			if err := tag.Write(buf); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+
+			tag2, err := ReadTag(buf)
+			if err != nil {
+				t.Fatalf("re-reading written tag failed: %v", err)
+			}
+			for id, val := range tc.frames {
+				fr := tag2.Get(id)
+				if fr == nil {
+					t.Fatalf("%v frame missing after round trip", id)
+				}
+				want := append([]byte{0x0}, []byte(val)...) // leading byte is the ISO-8859-1 encoding marker
+				if !bytes.Equal(fr.Data, want) {
+					t.Errorf("%v data after round trip = %x, want %x", id, fr.Data, want)
+				}
+			}
+			if len(buf.b) != len(orig) {
+				t.Errorf("Write changed overall tag size from %d to %d bytes", len(orig), len(buf.b))
+			}
+		})
+	}
+
+	t.Run("too large to fit", func(t *testing.T) {
+		raw := buildTag(t, 0, 16, map[string]string{"TIT2": "short"})
+		buf := &memBuf{b: raw}
+		tag, err := ReadTag(buf)
+		if err != nil {
+			t.Fatalf("ReadTag failed: %v", err)
+		}
+		tag.Set("TIT2", "a much, much longer title that no longer fits")
+		if err := tag.Write(buf); err == nil {
+			t.Errorf("Write succeeded despite tag growing past its original size")
+		}
+	})
+}
@@ -0,0 +1,26 @@
+// Package mp3fix inspects and repairs common problems in MP3 files: ID3v2
+// tags reporting the wrong size, and weakly-validated frame sync. It also
+// reads Xing/Info/VBRI headers and trailing ID3v1/APEv2 tags, but does not
+// rewrite Xing/Info/VBRI headers. Functions here that read or write file
+// data take an io.ReaderAt, a WriterAt, or a Truncater rather than a
+// concrete *os.File, so callers can operate on files, HTTP response bodies
+// (via an in-memory or ranged reader), zip members, or buffers.
+package mp3fix
+
+const (
+	// ID3HeaderSize is the size in bytes of the fixed part of an ID3v2
+	// header, before the tag data it describes.
+	ID3HeaderSize = 10
+)
+
+// WriterAt is the subset of *os.File used to patch bytes in place. It's
+// satisfied by *os.File and by wrappers around in-memory buffers.
+type WriterAt interface {
+	WriteAt(b []byte, off int64) (int, error)
+}
+
+// Truncater is implemented by destinations that support removing trailing
+// bytes, such as *os.File. It's used when stripping a trailing footer tag.
+type Truncater interface {
+	Truncate(size int64) error
+}
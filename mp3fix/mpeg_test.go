@@ -0,0 +1,164 @@
+package mp3fix
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// makeHeaderBytes builds a 4-byte MPEG-1 Layer III frame header with the
+// given field values. hasCRC sets the header's Protected field (a CRC
+// follows the header) and drives the on-the-wire protection bit, which is
+// the inverse: 0 means "CRC follows".
+func makeHeaderBytes(bitrateIdx, sampleRateIdx, modeIdx byte, hasCRC, padding bool) []byte {
+	b := make([]byte, 4)
+	b[0] = 0xff
+	b[1] = 0xe0 | 0x18 | 0x2 // sync, version=11 (MPEG1), layer=01 (Layer III)
+	if !hasCRC {
+		b[1] |= 0x1
+	}
+	b[2] = bitrateIdx<<4 | sampleRateIdx<<2
+	if padding {
+		b[2] |= 0x2
+	}
+	b[3] = modeIdx << 6
+	return b
+}
+
+func TestParseFrameHeader(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		b         []byte
+		wantErr   bool
+		wantBr    int
+		wantSR    int
+		wantMode  string
+		wantProt  bool
+		wantFrame int64
+	}{
+		{
+			name:      "valid 128kbps 44100 stereo",
+			b:         makeHeaderBytes(9, 0, 0, false, false),
+			wantBr:    128,
+			wantSR:    44100,
+			wantMode:  "stereo",
+			wantProt:  false,
+			wantFrame: 417,
+		},
+		{
+			name:      "valid with padding and crc",
+			b:         makeHeaderBytes(9, 0, 3, true, true),
+			wantBr:    128,
+			wantSR:    44100,
+			wantMode:  "mono",
+			wantProt:  true,
+			wantFrame: 418,
+		},
+		{
+			name:    "bad sync",
+			b:       []byte{0xff, 0x00, 0x00, 0x00},
+			wantErr: true,
+		},
+		{
+			name:    "reserved bitrate index",
+			b:       makeHeaderBytes(0xf, 0, 0, true, false),
+			wantErr: true,
+		},
+		{
+			name:    "free bitrate index",
+			b:       makeHeaderBytes(0, 0, 0, true, false),
+			wantErr: true,
+		},
+		{
+			name:    "reserved sample rate index",
+			b:       makeHeaderBytes(9, 3, 0, true, false),
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			hdr, err := parseFrameHeader(tc.b)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("parseFrameHeader(%x) = %+v, want error", tc.b, hdr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFrameHeader(%x) failed: %v", tc.b, err)
+			}
+			if hdr.Bitrate != tc.wantBr || hdr.SampleRate != tc.wantSR || hdr.ChannelMode != tc.wantMode ||
+				hdr.Protected != tc.wantProt || hdr.FrameLen != tc.wantFrame {
+				t.Errorf("parseFrameHeader(%x) = %+v, want {Bitrate:%d SampleRate:%d ChannelMode:%v Protected:%v FrameLen:%d}",
+					tc.b, hdr, tc.wantBr, tc.wantSR, tc.wantMode, tc.wantProt, tc.wantFrame)
+			}
+		})
+	}
+}
+
+func TestCRC16MPEG(t *testing.T) {
+	// The CRC is order-sensitive and non-trivial, so just check basic
+	// sanity properties rather than a single hardcoded vector.
+	if got := crc16MPEG(nil); got != 0xffff {
+		t.Errorf("crc16MPEG(nil) = 0x%x, want 0xffff (the initial value)", got)
+	}
+	a := crc16MPEG([]byte{0x01, 0x02, 0x03})
+	b := crc16MPEG([]byte{0x01, 0x02, 0x04})
+	if a == b {
+		t.Errorf("crc16MPEG produced the same value for different inputs: 0x%x", a)
+	}
+}
+
+func TestFindFirstFrame(t *testing.T) {
+	hdr := makeHeaderBytes(9, 0, 0, false, false) // 128kbps/44100/stereo, no CRC, frame length 417
+	frame := append(append([]byte{}, hdr...), bytes.Repeat([]byte{0x00}, 417-4)...)
+
+	t.Run("frame at start", func(t *testing.T) {
+		data := append(append([]byte{}, frame...), frame...)
+		offset, got, err := FindFirstFrame(bytes.NewReader(data), 0, 1)
+		if err != nil {
+			t.Fatalf("FindFirstFrame failed: %v", err)
+		}
+		if offset != 0 {
+			t.Errorf("FindFirstFrame offset = %d, want 0", offset)
+		}
+		if got.Bitrate != 128 {
+			t.Errorf("FindFirstFrame bitrate = %d, want 128", got.Bitrate)
+		}
+	})
+
+	t.Run("garbage then frame", func(t *testing.T) {
+		junk := bytes.Repeat([]byte{0xaa}, 10)
+		data := append(append(append([]byte{}, junk...), frame...), frame...)
+		offset, _, err := FindFirstFrame(readerAt(data), 0, 2048)
+		if err != nil {
+			t.Fatalf("FindFirstFrame failed: %v", err)
+		}
+		if offset != int64(len(junk)) {
+			t.Errorf("FindFirstFrame offset = %d, want %d", offset, len(junk))
+		}
+	})
+
+	t.Run("stray 0xff with no valid header doesn't match", func(t *testing.T) {
+		data := append([]byte{0xff, 0xff, 0xff, 0xff}, frame...)
+		offset, _, err := FindFirstFrame(readerAt(data), 0, 2048)
+		if err != nil {
+			t.Fatalf("FindFirstFrame failed: %v", err)
+		}
+		if offset != 4 {
+			t.Errorf("FindFirstFrame offset = %d, want 4 (should skip the bogus sync)", offset)
+		}
+	})
+
+	t.Run("no frame found", func(t *testing.T) {
+		data := bytes.Repeat([]byte{0x00}, 100)
+		if _, _, err := FindFirstFrame(readerAt(data), 0, 100); err == nil {
+			t.Errorf("FindFirstFrame unexpectedly succeeded on all-zero input")
+		}
+	})
+}
+
+// readerAt adapts a byte slice to io.ReaderAt; bytes.Reader already
+// implements it, but this keeps call sites above self-documenting.
+func readerAt(b []byte) io.ReaderAt {
+	return bytes.NewReader(b)
+}
@@ -0,0 +1,170 @@
+package mp3fix
+
+import (
+	"io"
+)
+
+// VBRKind identifies which variable-bitrate header format was found in an
+// MP3's first frame.
+type VBRKind int
+
+const (
+	VBRNone VBRKind = iota
+	VBRXing
+	VBRInfo
+	VBRVBRI
+)
+
+func (k VBRKind) String() string {
+	switch k {
+	case VBRXing:
+		return "Xing"
+	case VBRInfo:
+		return "Info"
+	case VBRVBRI:
+		return "VBRI"
+	default:
+		return "none"
+	}
+}
+
+const (
+	xingFlagFrames  = 0x1
+	xingFlagBytes   = 0x2
+	xingFlagTOC     = 0x4
+	xingFlagQuality = 0x8
+
+	vbriHeaderOffset = 36 // bytes from the start of the frame (header included)
+)
+
+// VBRHeader holds the fields of a Xing/Info or VBRI header found in an MP3's
+// first audio frame. These headers let players show an accurate duration
+// and seekbar for variable-bitrate files, which can't be computed from a
+// single frame's bitrate.
+type VBRHeader struct {
+	Kind    VBRKind
+	Frames  uint32
+	Bytes   uint32
+	Quality uint32    // Xing/Info quality field, or VBRI's 16-bit quality field
+	TOC     [100]byte // percentage -> relative byte offset lookup table
+}
+
+// xingOffset returns the byte offset of a Xing/Info tag relative to the
+// start of the side information that follows an MPEG-1 Layer III frame
+// header (and CRC, if present): 32 bytes for stereo/joint-stereo/dual
+// channel, or 17 for mono.
+func xingOffset(hdr FrameHeader) int64 {
+	if hdr.ChannelMode == "mono" {
+		return 17
+	}
+	return 32
+}
+
+// ReadVBRHeader looks for a Xing, Info, or VBRI header in the frame at
+// frameOffset and parses it if present. It returns a nil header (and no
+// error) if the frame doesn't contain one, which is the common case for
+// constant-bitrate files.
+func ReadVBRHeader(r io.ReaderAt, frameOffset int64, hdr FrameHeader) (*VBRHeader, error) {
+	base := frameOffset + 4
+	if hdr.Protected {
+		base += 2
+	}
+
+	xingStart := base + xingOffset(hdr)
+	if v, err := readXingHeader(r, xingStart); err != nil {
+		return nil, err
+	} else if v != nil {
+		return v, nil
+	}
+
+	return readVBRIHeader(r, frameOffset+vbriHeaderOffset)
+}
+
+func readXingHeader(r io.ReaderAt, offset int64) (*VBRHeader, error) {
+	tag := make([]byte, 8)
+	if _, err := r.ReadAt(tag, offset); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var kind VBRKind
+	switch string(tag[0:4]) {
+	case "Xing":
+		kind = VBRXing
+	case "Info":
+		kind = VBRInfo
+	default:
+		return nil, nil
+	}
+	flags := beUint32(tag[4:8])
+
+	v := &VBRHeader{Kind: kind}
+	pos := offset + 8
+	if flags&xingFlagFrames != 0 {
+		b := make([]byte, 4)
+		if _, err := r.ReadAt(b, pos); err != nil {
+			return nil, err
+		}
+		v.Frames = beUint32(b)
+		pos += 4
+	}
+	if flags&xingFlagBytes != 0 {
+		b := make([]byte, 4)
+		if _, err := r.ReadAt(b, pos); err != nil {
+			return nil, err
+		}
+		v.Bytes = beUint32(b)
+		pos += 4
+	}
+	if flags&xingFlagTOC != 0 {
+		if _, err := r.ReadAt(v.TOC[:], pos); err != nil {
+			return nil, err
+		}
+		pos += 100
+	}
+	if flags&xingFlagQuality != 0 {
+		b := make([]byte, 4)
+		if _, err := r.ReadAt(b, pos); err != nil {
+			return nil, err
+		}
+		v.Quality = beUint32(b)
+	}
+	return v, nil
+}
+
+func readVBRIHeader(r io.ReaderAt, offset int64) (*VBRHeader, error) {
+	b := make([]byte, 26)
+	if _, err := r.ReadAt(b, offset); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if string(b[0:4]) != "VBRI" {
+		return nil, nil
+	}
+
+	v := &VBRHeader{
+		Kind:    VBRVBRI,
+		Quality: uint32(beUint16(b[8:10])),
+		Bytes:   beUint32(b[10:14]),
+		Frames:  beUint32(b[14:18]),
+	}
+
+	tocEntries := beUint16(b[18:20])
+	tocEntrySize := int(beUint16(b[24:26]))
+	tocStart := offset + 26
+	n := int(tocEntries)
+	if n > len(v.TOC)/max(tocEntrySize, 1) {
+		n = len(v.TOC) / max(tocEntrySize, 1)
+	}
+	raw := make([]byte, n*tocEntrySize)
+	if _, err := r.ReadAt(raw, tocStart); err != nil && err != io.EOF {
+		return nil, err
+	}
+	copy(v.TOC[:], raw)
+
+	return v, nil
+}
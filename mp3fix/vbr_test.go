@@ -0,0 +1,82 @@
+package mp3fix
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadVBRHeader(t *testing.T) {
+	t.Run("xing header", func(t *testing.T) {
+		hdr := FrameHeader{ChannelMode: "stereo", Protected: false}
+		// base = frameOffset(0) + 4 (frame header) = 4; xingOffset(stereo) = 32.
+		data := make([]byte, 4+32+8+4+4+4)
+		tag := data[4+32:]
+		copy(tag[0:4], "Xing")
+		beutil32(tag[4:8], xingFlagFrames|xingFlagBytes|xingFlagQuality)
+		beutil32(tag[8:12], 1234)  // Frames
+		beutil32(tag[12:16], 5678) // Bytes
+		beutil32(tag[16:20], 100)  // Quality
+
+		v, err := ReadVBRHeader(bytes.NewReader(data), 0, hdr)
+		if err != nil {
+			t.Fatalf("ReadVBRHeader failed: %v", err)
+		}
+		if v == nil {
+			t.Fatalf("ReadVBRHeader found no header")
+		}
+		if v.Kind != VBRXing || v.Frames != 1234 || v.Bytes != 5678 || v.Quality != 100 {
+			t.Errorf("ReadVBRHeader = %+v, want {Kind:Xing Frames:1234 Bytes:5678 Quality:100}", v)
+		}
+	})
+
+	t.Run("info header (mono, protected)", func(t *testing.T) {
+		hdr := FrameHeader{ChannelMode: "mono", Protected: true}
+		// base = 4 + 2 (CRC) = 6; xingOffset(mono) = 17.
+		data := make([]byte, 6+17+8+4+4)
+		tag := data[6+17:]
+		copy(tag[0:4], "Info")
+		beutil32(tag[4:8], xingFlagFrames|xingFlagBytes)
+		beutil32(tag[8:12], 10)
+		beutil32(tag[12:16], 20)
+
+		v, err := ReadVBRHeader(bytes.NewReader(data), 0, hdr)
+		if err != nil {
+			t.Fatalf("ReadVBRHeader failed: %v", err)
+		}
+		if v == nil || v.Kind != VBRInfo || v.Frames != 10 || v.Bytes != 20 {
+			t.Errorf("ReadVBRHeader = %+v, want {Kind:Info Frames:10 Bytes:20}", v)
+		}
+	})
+
+	t.Run("vbri header", func(t *testing.T) {
+		hdr := FrameHeader{ChannelMode: "stereo", Protected: false}
+		data := make([]byte, vbriHeaderOffset+26)
+		tag := data[vbriHeaderOffset:]
+		copy(tag[0:4], "VBRI")
+		beutil16(tag[8:10], 50) // Quality
+		beutil32(tag[10:14], 999)
+		beutil32(tag[14:18], 42)
+		beutil16(tag[18:20], 0) // no TOC entries
+		beutil16(tag[24:26], 2) // entry size, unused when count is 0
+
+		v, err := ReadVBRHeader(bytes.NewReader(data), 0, hdr)
+		if err != nil {
+			t.Fatalf("ReadVBRHeader failed: %v", err)
+		}
+		if v == nil || v.Kind != VBRVBRI || v.Bytes != 999 || v.Frames != 42 || v.Quality != 50 {
+			t.Errorf("ReadVBRHeader = %+v, want {Kind:VBRI Bytes:999 Frames:42 Quality:50}", v)
+		}
+	})
+
+	t.Run("no vbr header present", func(t *testing.T) {
+		hdr := FrameHeader{ChannelMode: "stereo", Protected: false}
+		data := make([]byte, 4+32+8)
+		v, err := ReadVBRHeader(bytes.NewReader(data), 0, hdr)
+		if err != nil {
+			t.Fatalf("ReadVBRHeader failed: %v", err)
+		}
+		if v != nil {
+			t.Errorf("ReadVBRHeader = %+v, want nil", v)
+		}
+	})
+}